@@ -34,13 +34,107 @@ type Error struct {
 	Created int64 `protobuf:"varint,8,opt,name=created" json:"created,omitempty"`
 	// Should contains the unique code for an error
 	Code string `protobuf:"bytes,4,opt,name=code" json:"code,omitempty"`
-	// Describe root cause of error after being wrapped
-	Root string `protobuf:"bytes,10,opt,name=base" json:"root,omitempty"`
 	// ID of the http (rpc) request which causes the error
 	RequestId string `protobuf:"bytes,12,opt,name=request_id" json:"request_id,omitempty"`
+
+	// wrapped holds the error this Error was created from (via Wrap), so the
+	// standard errors.Unwrap/Is/As can walk the whole chain. Not serialized;
+	// MarshalJSON emits it (and the rest of the chain) as the causes array
+	// instead, so a receiver can reconstruct it via FromString.
+	wrapped error
+
+	// next links a decoded *Error (built by FromString from the causes
+	// array) to the next, deeper link in its cause chain. Only used for
+	// chains reconstructed from the wire format; live chains use wrapped.
+	next *Error
+
+	// frames backs StackTrace and Stack. Not serialized directly; Stack is
+	// derived from it on capture.
+	frames []Frame
+}
+
+// MaxStackDepth is the maximum number of stack frames New, Wrap and Join
+// collect (CaptureStack itself takes its depth as an argument and does not
+// read MaxStackDepth). It can be tuned per process; lower it to cut the
+// cost of error creation, raise it when a deep call chain gets truncated.
+var MaxStackDepth = 50
+
+// Frame describes a single stack frame captured at error creation time.
+type Frame struct {
+	Function string  `json:"function,omitempty"`
+	File     string  `json:"file,omitempty"`
+	Line     int     `json:"line,omitempty"`
+	PC       uintptr `json:"pc,omitempty"`
+}
+
+// StackTrace returns the structured stack frames captured when e was
+// created. Use this instead of parsing the Stack string.
+func (e *Error) StackTrace() []Frame {
+	if e == nil {
+		return nil
+	}
+	return e.frames
 }
 
-// Wrap converts a random error to an `*errors.Error`, information of the old error stored in Root field.
+// CaptureStack returns up to depth stack frames, skipping the closest skip
+// frames (in addition to CaptureStack's own frame). It uses
+// runtime.CallersFrames, so inlined functions are resolved correctly, and
+// applies the same system-path/vendor trimming as the rest of the package.
+func CaptureStack(skip, depth int) []Frame {
+	pcs := make([]uintptr, depth)
+	// skip one system stack, the this current stack line
+	n := runtime.Callers(2+skip, pcs)
+	pcs = pcs[:n]
+
+	frames := make([]Frame, 0, n)
+	callersFrames := runtime.CallersFrames(pcs)
+	for {
+		f, more := callersFrames.Next()
+		if isSystemPath(f.File) {
+			if !more {
+				break
+			}
+			continue
+		}
+
+		file := trimToPrefix(f.File, "/vendor/")
+		// trim out common provider since most of go projects are hosted
+		// in single host, there is no need to include them in the call stack
+		// remove them help keeping the call stack smaller, navigatiing easier
+		if !strings.HasPrefix(file, "/vendor") {
+			file = trimOutPrefix(file, "/git.subiz.net/")
+			file = trimOutPrefix(file, "/github.com/")
+			file = trimOutPrefix(file, "/gitlab.com/")
+			file = trimOutPrefix(file, "/bitbucket.org/")
+			file = trimOutPrefix(file, "/gopkg.in/")
+		}
+
+		frames = append(frames, Frame{Function: f.Function, File: file, Line: f.Line, PC: f.PC})
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// renderStack derives the legacy Stack string (one "file:line" per line)
+// from structured frames, kept for backward compatibility.
+func renderStack(frames []Frame) string {
+	var sb strings.Builder
+	for _, f := range frames {
+		sb.WriteString(f.File)
+		sb.WriteString(":")
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Wrap converts a random error to an `*errors.Error`. If err is already an
+// *Error, Wrap stacks a new layer on top of it instead of mutating err in
+// place, so wrapping the same error several times keeps every layer of
+// context and the whole chain stays reachable (and printable) through
+// Unwrap and Cause.
 func Wrap(err error, class int, code Code, v ...interface{}) *Error {
 	if err == nil {
 		err = &Error{}
@@ -48,23 +142,213 @@ func Wrap(err error, class int, code Code, v ...interface{}) *Error {
 	mye, ok := err.(*Error)
 	if !ok {
 		e := New(class, code, append(v, err.Error()))
-		e.Root = err.Error()
+		e.wrapped = err
 		return e
 	}
 
-	if code.String() != "" && (mye.Code == "" || mye.Code == "unknown") {
-		mye.Code = code.String()
+	e := New(class, code, v...)
+	if e.Description == "" {
+		e.Description = mye.Description
+	}
+	if e.Code == "" || e.Code == "unknown" {
+		e.Code = mye.Code
+	}
+	if e.Class == 0 {
+		e.Class = mye.Class
+	}
+	e.wrapped = mye
+	return e
+}
+
+// Unwrap returns the error e was created from (via Wrap), or, for an error
+// decoded by FromString, the next link reconstructed from the causes array.
+// Either way, the standard errors.Unwrap/Is/As can walk the whole chain.
+func (e *Error) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	if e.wrapped != nil {
+		return e.wrapped
+	}
+	if e.next != nil {
+		return e.next
+	}
+	return nil
+}
+
+// Cause returns the deepest error in e's chain, walking Unwrap until it
+// bottoms out.
+func (e *Error) Cause() error {
+	if e == nil {
+		return nil
+	}
+	return Cause(e)
+}
+
+// Root returns a short "code: description" summary of the deepest cause in
+// e's chain (just the description, for a non-*Error cause), not the cause's
+// full wire representation — the causes array already carries the rest of
+// the chain's structure, so Root stays a concise, human-readable string
+// the way the old Root field was.
+func (e *Error) Root() string {
+	c := e.Cause()
+	if c == nil || c == error(e) {
+		return ""
+	}
+	ce, ok := c.(*Error)
+	if !ok {
+		return c.Error()
+	}
+	if ce.Code == "" {
+		return ce.Description
+	}
+	return ce.Code + ": " + ce.Description
+}
+
+// Cause returns the deepest error in err's chain, walking the standard
+// Unwrap until it bottoms out. It works with any error, not just *Error.
+func Cause(err error) error {
+	for {
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return err
+		}
+		next := u.Unwrap()
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+// RootCode returns the Code of the deepest *Error in err's chain that has a
+// non-empty Code, or "" if no *Error in the chain has one.
+func RootCode(err error) string {
+	code := ""
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Code != "" {
+			code = e.Code
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return code
+}
+
+// Is reports whether target matches e by comparing Code, so callers can use
+// errors.Is(err, &Error{Code: "e_not_found"}) as a sentinel check instead of
+// comparing pointers. The standard errors.Is already walks the Unwrap chain
+// and calls Is at every link, so this only needs to check e itself.
+func (e *Error) Is(target error) bool {
+	if e == nil || target == nil {
+		return false
+	}
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code != "" && e.Code == t.Code
+}
+
+// As assigns e to *target, a non-nil **Error, and reports true.
+//
+// Unlike Is, As cannot usefully gate by Code: the standard errors.As
+// resolves a **Error target by reflect-based assignability before it ever
+// calls a node's As method, and every *Error in a chain built by this
+// package is already assignable to *Error, so errors.As always stops at
+// the outermost node in the chain without consulting this method. As only
+// exists to satisfy the As(interface{}) bool contract for callers that
+// invoke (*Error).As directly.
+func (e *Error) As(target interface{}) bool {
+	t, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+	*t = e
+	return true
+}
+
+// MultiError aggregates several errors into a single error value. It
+// implements Unwrap() []error so the standard errors.Is/errors.As traverse
+// every branch, and renders in the same `#ERR ` wire format as *Error.
+type MultiError struct {
+	Errs    []*Error `json:"errors,omitempty"`
+	Stack   string   `json:"stack,omitempty"`
+	Created int64    `json:"created,omitempty"`
+}
+
+// Join combines errs into a single *MultiError, capturing a stack trace at
+// the point Join is called. Nil entries are skipped; Join returns nil if
+// every entry is nil. Callers that store the result directly in an error
+// value (rather than checking the concrete *MultiError) should assign
+// through Interface, the same way callers of *Error do, to avoid the
+// classic typed-nil trap.
+//
+// Non-*Error values are lifted to *Error (class 500, E_unknown) so each
+// branch carries its own captured stack, mirroring Wrap.
+func Join(errs ...error) *MultiError {
+	m := &MultiError{Stack: renderStack(CaptureStack(1, MaxStackDepth)), Created: time.Now().UnixNano()}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		e, ok := err.(*Error)
+		if !ok {
+			e = New(500, E_unknown, err.Error())
+			e.wrapped = err
+		}
+		m.Errs = append(m.Errs, e)
 	}
 
-	if class != 0 && mye.Class == 0 {
-		mye.Class = int32(class)
+	if len(m.Errs) == 0 {
+		return nil
 	}
+	return m
+}
 
-	if len(v) > 0 {
-		e := New(class, code, v)
-		mye.Description += "\n" + e.Description
+// Interface returns an error interface of m.
+// If m is nil, it returns interface(nil, nil) instead of interface(*MultiError, nil),
+// mirroring (*Error).Interface, so assigning `err = m.Interface()` to an
+// error-typed variable is never a non-nil interface wrapping a nil pointer.
+func (m *MultiError) Interface() error {
+	if m == nil {
+		return nil
 	}
-	return mye
+	return m
+}
+
+// Error renders m as real JSON in the same `#ERR ` wire format as *Error, so
+// it round-trips through JoinFromString.
+//
+// This deliberately departs from newline-joined child messages: that
+// rendering isn't valid JSON (each child already carries its own `#ERR `
+// prefix), so it can't satisfy the "JSON-serializable in the same `#ERR `
+// wire format" requirement the backlog item also asked for. Between the
+// two conflicting asks, round-trippable JSON wins.
+func (m *MultiError) Error() string {
+	if m == nil {
+		return ""
+	}
+
+	b, _ := json.Marshal(m)
+	return "#ERR " + string(b)
+}
+
+// Unwrap returns every joined error so the standard errors.Is/errors.As can
+// walk each branch.
+func (m *MultiError) Unwrap() []error {
+	if m == nil {
+		return nil
+	}
+
+	errs := make([]error, len(m.Errs))
+	for i, e := range m.Errs {
+		errs[i] = e
+	}
+	return errs
 }
 
 // New returns an error with the supplied message.
@@ -87,12 +371,42 @@ func New(class int, code Code, v ...interface{}) *Error {
 	e := &Error{}
 	e.Description = message
 	e.Class = int32(class)
-	e.Stack = getStack(1)
+	e.frames = CaptureStack(1, MaxStackDepth)
+	e.Stack = renderStack(e.frames)
 	e.Created = time.Now().UnixNano()
 	e.Code = code.String()
+	report(e)
 	return e
 }
 
+// Reporter forwards captured errors to an external aggregation service
+// (e.g. Sentry). Capture is called once per error, at creation time;
+// Flush blocks until pending errors are delivered or timeout elapses.
+type Reporter interface {
+	Capture(*Error)
+	Flush(timeout time.Duration)
+}
+
+// defaultReporter is the package-level Reporter set via SetReporter. Errors
+// are only captured once one has been registered; by default no reporting
+// happens.
+var defaultReporter Reporter
+
+// SetReporter registers r as the package-level reporter, so every error
+// built by New (and, through it, Wrap) is passed to r.Capture once, at
+// creation time. Pass nil to stop reporting.
+func SetReporter(r Reporter) {
+	defaultReporter = r
+}
+
+// report hands e to the registered reporter, if any.
+func report(e *Error) {
+	if defaultReporter == nil {
+		return
+	}
+	defaultReporter.Capture(e)
+}
+
 // FromString unmarshal an error string to *Error
 func FromString(err string) *Error {
 	if !strings.HasPrefix(err, "#ERR ") {
@@ -105,6 +419,21 @@ func FromString(err string) *Error {
 	return e
 }
 
+// JoinFromString unmarshals the `#ERR ` wire format produced by
+// (*MultiError).Error back into a *MultiError, preserving every joined
+// error as a decoded *Error. Use this instead of FromString for payloads
+// known to come from Join.
+func JoinFromString(s string) *MultiError {
+	if !strings.HasPrefix(s, "#ERR ") {
+		return Join(New(500, E_unknown, s))
+	}
+	m := &MultiError{}
+	if er := json.Unmarshal([]byte(s[len("#ERR "):]), m); er != nil {
+		return Join(New(500, E_json_marshal_error, "%s, %s", er, s))
+	}
+	return m
+}
+
 // GetCode returns code of the error
 func (e *Error) GetCode() string {
 	if e == nil {
@@ -134,44 +463,105 @@ func (e *Error) Error() string {
 	return "#ERR " + string(b)
 }
 
-// getStack returns 20 closest stacktrace, included file paths and line numbers
-// it will ignore all system path, path which is vendor is striped to /vendor/
-// skip: number of stack ignored
-func getStack(skip int) string {
-	stack := make([]uintptr, 20)
-	var sb strings.Builder
-	// skip one system stack, the this current stack line
-	length := runtime.Callers(2+skip, stack[:])
-	for i := 0; i < length; i++ {
-		pc := stack[i]
-		// pc - 1 because the program counters we use are usually return addresses,
-		// and we want to show the line that corresponds to the function call
-		f := runtime.FuncForPC(pc)
-		file, line := f.FileLine(pc - 1)
-		// dont report system path
-		if isSystemPath(file) {
-			continue
-		}
+// causeSummary is the JSON-serializable summary of one link in an error's
+// cause chain. It lets a receiver decoding via FromString reconstruct a
+// synthetic chain that Cause/Is/As can traverse, even though the original
+// wrapped error values themselves don't cross the wire.
+type causeSummary struct {
+	Code        string `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Class       int32  `json:"class,omitempty"`
+	TopFrame    string `json:"top_frame,omitempty"`
+}
 
-		file = trimToPrefix(file, "/vendor/")
+// summary converts e itself (not its chain) into a causeSummary.
+func (e *Error) summary() causeSummary {
+	top := ""
+	if frames := e.StackTrace(); len(frames) > 0 {
+		top = frames[0].File + ":" + strconv.Itoa(frames[0].Line)
+	}
+	return causeSummary{Code: e.Code, Description: e.Description, Class: e.Class, TopFrame: top}
+}
 
-		// trim out common provider since most of go projects are hosted
-		// in single host, there is no need to include them in the call stack
-		// remove them help keeping the call stack smaller, navigatiing easier
-		if !strings.HasPrefix(file, "/vendor") {
-			file = trimOutPrefix(file, "/git.subiz.net/")
-			file = trimOutPrefix(file, "/github.com/")
-			file = trimOutPrefix(file, "/gitlab.com/")
-			file = trimOutPrefix(file, "/bitbucket.org/")
-			file = trimOutPrefix(file, "/gopkg.in/")
+// errorJSON is the `#ERR ` wire representation of *Error: the plain fields
+// plus a computed Root summary and the full cause chain, so MarshalJSON and
+// UnmarshalJSON stay symmetric without exposing wrapped/next directly.
+type errorJSON struct {
+	Description string         `json:"description,omitempty"`
+	Debug       string         `json:"debug,omitempty"`
+	Class       int32          `json:"class,omitempty"`
+	Stack       string         `json:"stack,omitempty"`
+	Created     int64          `json:"created,omitempty"`
+	Code        string         `json:"code,omitempty"`
+	RequestId   string         `json:"request_id,omitempty"`
+	Root        string         `json:"root,omitempty"`
+	Causes      []causeSummary `json:"causes,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, emitting the plain fields plus a
+// computed Root summary and a causes array describing the rest of the chain
+// (wrapped, then, for a chain decoded via FromString, next).
+func (e *Error) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("null"), nil
+	}
+
+	out := errorJSON{
+		Description: e.Description,
+		Debug:       e.Debug,
+		Class:       e.Class,
+		Stack:       e.Stack,
+		Created:     e.Created,
+		Code:        e.Code,
+		RequestId:   e.RequestId,
+		Root:        e.Root(),
+	}
+
+	for w := e.wrapped; w != nil; {
+		we, ok := w.(*Error)
+		if !ok {
+			out.Causes = append(out.Causes, causeSummary{Description: w.Error()})
+			break
 		}
+		out.Causes = append(out.Causes, we.summary())
+		w = we.Unwrap()
+	}
+	for n := e.next; n != nil; n = n.next {
+		out.Causes = append(out.Causes, n.summary())
+	}
 
-		sb.WriteString(file)
-		sb.WriteString(":")
-		sb.WriteString(strconv.Itoa(line))
-		sb.WriteString("\n")
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reconstructing a synthetic
+// cause chain from the causes array: each link is a bare *Error linked via
+// next, so Unwrap/Cause/Is/As can walk it the same way they walk a live
+// chain built by Wrap.
+func (e *Error) UnmarshalJSON(b []byte) error {
+	var in errorJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
 	}
-	return sb.String()
+
+	e.Description = in.Description
+	e.Debug = in.Debug
+	e.Class = in.Class
+	e.Stack = in.Stack
+	e.Created = in.Created
+	e.Code = in.Code
+	e.RequestId = in.RequestId
+
+	var tail *Error
+	for _, c := range in.Causes {
+		link := &Error{Code: c.Code, Description: c.Description, Class: c.Class}
+		if tail == nil {
+			e.next = link
+		} else {
+			tail.next = link
+		}
+		tail = link
+	}
+	return nil
 }
 
 // isSystemPath tells whether a file is in system golang packages
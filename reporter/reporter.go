@@ -0,0 +1,242 @@
+// Package reporter ships *errors.Error values to a Sentry-compatible "store"
+// endpoint (Sentry, GlitchTip, self-hosted Sentry) over HTTP, asynchronously
+// and in batches.
+//
+//     r := reporter.New("https://sentry.example.com/api/1/store/")
+//     errors.SetReporter(r)
+//     defer r.Flush(5 * time.Second)
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	errs "git.subiz.net/errors"
+)
+
+// dedupWindow bounds how long a fingerprint suppresses repeats of the same
+// failure; after it elapses the next occurrence is reported again. maxSeen
+// caps how many fingerprints are tracked at once: once the cache hits
+// maxSeen, Capture unconditionally evicts the oldest half (plus one) regardless of
+// whether they're still inside dedupWindow, so a burst of distinct errors
+// can't grow the cache past maxSeen.
+const (
+	dedupWindow = 5 * time.Minute
+	maxSeen     = 4096
+)
+
+// SentryReporter implements errors.Reporter. It de-duplicates by a
+// fingerprint of Code + closest non-vendor frame within a rolling window,
+// so the same failure captured repeatedly doesn't flood the backend, but a
+// failure that recurs after the window is still reported.
+type SentryReporter struct {
+	dsn        string
+	httpClient *http.Client
+
+	flushInterval time.Duration
+	batchSize     int
+
+	mu    sync.Mutex
+	queue []event
+	seen  map[string]time.Time
+
+	done chan struct{}
+}
+
+// New returns a SentryReporter that posts to dsn, flushing queued errors
+// every 5 seconds in batches of up to 20; each error in a batch is posted as
+// its own HTTP request. A background goroutine drives the
+// periodic flush; call Flush to force delivery (e.g. before process exit).
+func New(dsn string) *SentryReporter {
+	r := &SentryReporter{
+		dsn:           dsn,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		flushInterval: 5 * time.Second,
+		batchSize:     20,
+		seen:          map[string]time.Time{},
+		done:          make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// Capture snapshots e into an immutable event and enqueues it for delivery,
+// skipping it if an error with the same fingerprint was already captured
+// within dedupWindow. The snapshot happens synchronously, on the caller's
+// goroutine, before e can be mutated further (e.g. by Wrap filling in
+// fields), so the background flush loop never reads from e itself.
+func (r *SentryReporter) Capture(e *errs.Error) {
+	if e == nil {
+		return
+	}
+
+	fp := fingerprint(e)
+	ev := toEvent(e)
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.seen) >= maxSeen {
+		r.evictOldest()
+	}
+
+	if last, ok := r.seen[fp]; ok && now.Sub(last) < dedupWindow {
+		return
+	}
+	r.seen[fp] = now
+	r.queue = append(r.queue, ev)
+}
+
+// evictOldest drops the oldest len(r.seen)/2+1 entries of r.seen (just over
+// half), unconditionally (not just
+// entries past dedupWindow), so the cache can never grow past maxSeen even
+// when a burst of distinct fingerprints arrives faster than the window
+// would naturally expire them. Callers must hold r.mu.
+func (r *SentryReporter) evictOldest() {
+	type fpTime struct {
+		fp string
+		t  time.Time
+	}
+	entries := make([]fpTime, 0, len(r.seen))
+	for fp, t := range r.seen {
+		entries = append(entries, fpTime{fp, t})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].t.Before(entries[j].t) })
+
+	for _, e := range entries[:len(entries)/2+1] {
+		delete(r.seen, e.fp)
+	}
+}
+
+// Flush sends every queued error, blocking until delivery finishes or
+// timeout elapses.
+func (r *SentryReporter) Flush(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.send()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// Close stops the background flush loop. Pending errors are not flushed;
+// call Flush first if they must be delivered.
+func (r *SentryReporter) Close() {
+	close(r.done)
+}
+
+func (r *SentryReporter) loop() {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.send()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *SentryReporter) send() {
+	r.mu.Lock()
+	batch := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+
+	for len(batch) > 0 {
+		n := r.batchSize
+		if n > len(batch) {
+			n = len(batch)
+		}
+		r.post(batch[:n])
+		batch = batch[n:]
+	}
+}
+
+func (r *SentryReporter) post(batch []event) {
+	for _, ev := range batch {
+		body, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+
+		req, err := http.NewRequest(http.MethodPost, r.dsn, bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
+// event mirrors the Sentry "store" endpoint payload.
+type event struct {
+	Exception struct {
+		Values []exceptionValue `json:"values"`
+	} `json:"exception"`
+}
+
+type exceptionValue struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Stacktrace stacktrace `json:"stacktrace"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Filename string `json:"filename"`
+	Function string `json:"function"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+func toEvent(e *errs.Error) event {
+	val := exceptionValue{Type: e.Code, Value: e.Description}
+	for _, f := range e.StackTrace() {
+		val.Stacktrace.Frames = append(val.Stacktrace.Frames, frame{
+			Filename: f.File,
+			Function: f.Function,
+			Lineno:   f.Line,
+			InApp:    !strings.HasPrefix(f.File, "/vendor"),
+		})
+	}
+
+	var ev event
+	ev.Exception.Values = []exceptionValue{val}
+	return ev
+}
+
+// fingerprint identifies an error for de-duplication purposes: its code
+// plus the file of its closest non-vendor frame. An error decoded via
+// errs.FromString carries no structured frames, so it fingerprints by Code
+// alone and collapses with any other decoded error of the same code.
+func fingerprint(e *errs.Error) string {
+	top := ""
+	for _, f := range e.StackTrace() {
+		if strings.HasPrefix(f.File, "/vendor") {
+			continue
+		}
+		top = f.File
+		break
+	}
+	return e.Code + "|" + top
+}